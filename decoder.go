@@ -0,0 +1,110 @@
+package qtos
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// defaultMaxDepth is the maximum number of nested keys (e.g. struct fields,
+// slice indices, map keys) a Decoder will follow before giving up. It exists
+// to guard against pathological keys like "a[b][c][d]..." that would
+// otherwise recurse once per key segment, e.g. through a self-referential
+// pointer type.
+const defaultMaxDepth = 32
+
+// KeyStyle controls which key grammars a Decoder accepts for nested structs
+// and maps.
+type KeyStyle int
+
+const (
+	// BracketStyle only accepts "sub[field]" for nested structs and
+	// "map.key" for maps with string keys.
+	BracketStyle KeyStyle = iota
+	// DotStyle only accepts "sub.field" for nested structs and
+	// "map[key]" for maps.
+	DotStyle
+	// BothStyles accepts either grammar.
+	BothStyles
+)
+
+// Decoder decodes url.Values into Go values. Unlike the package-level
+// Unmarshal, a Decoder's options are safe to configure per instance, making
+// it possible to use different settings for different requests.
+type Decoder struct {
+	tag            string
+	strict         bool
+	maxDepth       int
+	keyStyle       KeyStyle
+	unknownKeyFunc func(key string, value []string) error
+}
+
+// NewDecoder returns a Decoder using the package defaults: the StructTag tag
+// name, non-strict unknown field handling, DotStyle keys and a max depth of
+// 32.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		tag:      StructTag,
+		maxDepth: defaultMaxDepth,
+		keyStyle: DotStyle,
+	}
+}
+
+// WithTag sets the struct tag key used to look up field names. The default
+// is the value of StructTag at the time NewDecoder was called.
+func (d *Decoder) WithTag(tag string) *Decoder {
+	d.tag = tag
+	return d
+}
+
+// WithStrict makes Decode return an error when a key refers to a struct
+// field that doesn't exist, instead of silently ignoring it.
+func (d *Decoder) WithStrict(strict bool) *Decoder {
+	d.strict = strict
+	return d
+}
+
+// WithMaxDepth sets the maximum number of nested keys Decode will follow. A
+// value <= 0 disables the check.
+func (d *Decoder) WithMaxDepth(maxDepth int) *Decoder {
+	d.maxDepth = maxDepth
+	return d
+}
+
+// WithKeyStyle sets which key grammars are accepted for nested structs and
+// maps.
+func (d *Decoder) WithKeyStyle(style KeyStyle) *Decoder {
+	d.keyStyle = style
+	return d
+}
+
+// WithUnknownKeyFunc sets a function that is called whenever a key refers to
+// a struct field that doesn't exist. When fn is set it takes precedence over
+// WithStrict: if fn returns an error Decode stops and returns it, otherwise
+// the key is ignored.
+func (d *Decoder) WithUnknownKeyFunc(fn func(key string, value []string) error) *Decoder {
+	d.unknownKeyFunc = fn
+	return d
+}
+
+// Decode parses values and stores the result in the value pointed to by v.
+// For examples and supported formats see the tests.
+func (d *Decoder) Decode(values url.Values, v interface{}) error {
+	typ := reflect.TypeOf(v)
+	val := reflect.ValueOf(v)
+
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf("v must be a pointer")
+	} else {
+		typ = typ.Elem()
+		val = val.Elem()
+	}
+
+	for name, value := range values {
+		if err := d.bind(typ, val, "", name, value, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package qtos
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderWithTag(t *testing.T) {
+	type base struct {
+		String string `json:"string"`
+	}
+
+	values, err := url.ParseQuery("string=test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v base
+	if err := NewDecoder().WithTag("json").Decode(values, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.String != "test" {
+		t.Fatalf("expected String to be %q, got %q", "test", v.String)
+	}
+}
+
+func TestDecoderWithStrict(t *testing.T) {
+	type base struct {
+		String string `query:"string"`
+	}
+
+	values, err := url.ParseQuery("unknown=test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v base
+	if err := NewDecoder().WithStrict(true).Decode(values, &v); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Without strict mode the unknown key is silently ignored.
+	if err := NewDecoder().Decode(values, &v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoderWithStrictNested(t *testing.T) {
+	type inner struct {
+		Int int `query:"int"`
+	}
+
+	type base struct {
+		Inner inner `query:"inner"`
+	}
+
+	values, err := url.ParseQuery("inner.badname.deep=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v base
+	err = NewDecoder().WithStrict(true).Decode(values, &v)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The error should mention the full remaining key, not just the
+	// unknown field name.
+	if want := "inner.badname.deep"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected error to contain %q, got %q", want, err.Error())
+	}
+}
+
+func TestDecoderWithMaxDepth(t *testing.T) {
+	type node struct {
+		Next *node `query:"next"`
+		Int  int   `query:"int"`
+	}
+
+	values, err := url.ParseQuery("next.next.next.next.int=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v node
+	if err := NewDecoder().WithMaxDepth(2).Decode(values, &v); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if err := NewDecoder().WithMaxDepth(0).Decode(values, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Next.Next.Next.Next.Int != 2 {
+		t.Fatalf("expected a max depth of 0 to disable the check, got %#v", v)
+	}
+}
+
+func TestDecoderWithKeyStyle(t *testing.T) {
+	type address struct {
+		City string `query:"city"`
+	}
+
+	type user struct {
+		Address address           `query:"address"`
+		Tags    map[string]string `query:"tags"`
+	}
+
+	expected := user{
+		Address: address{City: "NYC"},
+		Tags:    map[string]string{"color": "red"},
+	}
+
+	bracketValues, err := url.ParseQuery("user[address][city]=NYC&user[tags].color=red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dotValues, err := url.ParseQuery("user.address.city=NYC&user.tags[color]=red")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type outer struct {
+		User user `query:"user"`
+	}
+
+	for _, tc := range []struct {
+		name   string
+		style  KeyStyle
+		values url.Values
+	}{
+		{"bracket", BracketStyle, bracketValues},
+		{"dot", DotStyle, dotValues},
+		{"both-bracket", BothStyles, bracketValues},
+		{"both-dot", BothStyles, dotValues},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var v outer
+			if err := NewDecoder().WithKeyStyle(tc.style).Decode(tc.values, &v); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v.User, expected) {
+				t.Fatalf("expected\n%#v\ngot\n%#v", expected, v.User)
+			}
+		})
+	}
+}
+
+func TestDecoderWithKeyStyleRejectsMixedNotation(t *testing.T) {
+	type address struct {
+		City string `query:"city"`
+	}
+	type user struct {
+		Address address `query:"address"`
+	}
+	type outer struct {
+		User user `query:"user"`
+	}
+
+	bracketValues, err := url.ParseQuery("user[address][city]=NYC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dotValues, err := url.ParseQuery("user.address.city=NYC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v outer
+	if err := NewDecoder().WithKeyStyle(DotStyle).Decode(bracketValues, &v); err == nil {
+		t.Fatal("expected DotStyle to reject bracket notation")
+	}
+	if err := NewDecoder().WithKeyStyle(BracketStyle).Decode(dotValues, &v); err == nil {
+		t.Fatal("expected BracketStyle to reject dot notation")
+	}
+}
+
+func TestDecoderWithUnknownKeyFunc(t *testing.T) {
+	type base struct {
+		String string `query:"string"`
+	}
+
+	values, err := url.ParseQuery("unknown=test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen string
+	fn := func(key string, value []string) error {
+		seen = key
+		return nil
+	}
+
+	var v base
+	if err := NewDecoder().WithUnknownKeyFunc(fn).Decode(values, &v); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "unknown" {
+		t.Fatalf("expected unknownKeyFunc to be called with %q, got %q", "unknown", seen)
+	}
+
+	errFn := func(key string, value []string) error {
+		return fmt.Errorf("unexpected key %s", key)
+	}
+	if err := NewDecoder().WithUnknownKeyFunc(errFn).Decode(values, &v); err == nil {
+		t.Fatal("expected an error")
+	}
+}
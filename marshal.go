@@ -0,0 +1,236 @@
+package qtos
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueryMarshaler is implemented by types that can encode themselves into a
+// single query string value. It is the inverse of QueryUnmarshaler.
+type QueryMarshaler interface {
+	MarshalQuery() (string, error)
+}
+
+// QueryUnmarshaler is implemented by types that can decode themselves from a
+// single query string value. It is the inverse of QueryMarshaler.
+type QueryUnmarshaler interface {
+	UnmarshalQuery(value string) error
+}
+
+var queryUnmarshalerType = reflect.TypeOf((*QueryUnmarshaler)(nil)).Elem()
+
+// Marshal encodes v into url.Values using the same bracket-and-dot key
+// conventions understood by Unmarshal. v must be a struct, map, slice, array
+// or a pointer to one of those. For examples and supported formats see the
+// tests.
+func Marshal(v interface{}) (url.Values, error) {
+	typ := reflect.TypeOf(v)
+	val := reflect.ValueOf(v)
+
+	if typ == nil {
+		return nil, fmt.Errorf("v must not be nil")
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, fmt.Errorf("v must not be nil")
+		}
+		typ = typ.Elem()
+		val = val.Elem()
+	}
+
+	values := url.Values{}
+	if err := marshal(typ, val, "", values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// marshal is called recursively while building up the key. key contains the
+// encoded part so far, mirroring the base argument in bind.
+func marshal(typ reflect.Type, val reflect.Value, key string, values url.Values) error {
+	if typ.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		return marshal(typ.Elem(), val.Elem(), key, values)
+	}
+
+	if typ.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		return marshal(val.Elem().Type(), val.Elem(), key, values)
+	}
+
+	if s, ok, err := marshalText(val); err != nil {
+		return err
+	} else if ok {
+		values.Add(key, s)
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			styp := typ.Field(i)
+
+			// Only exported fields can be encoded.
+			if styp.PkgPath != "" {
+				continue
+			}
+
+			name, omitempty := fieldTag(styp)
+			if name == "-" {
+				continue
+			}
+			if omitempty && val.Field(i).IsZero() {
+				continue
+			}
+
+			fieldKey := name
+			if key != "" {
+				fieldKey = key + "." + name
+			}
+
+			if err := marshal(styp.Type, val.Field(i), fieldKey, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			childKey := fmt.Sprintf("%s[%d]", key, i)
+			if err := marshal(typ.Elem(), val.Index(i), childKey, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		keys := val.MapKeys()
+		keyStrs := make([]string, len(keys))
+		for i, k := range keys {
+			s, err := formatScalar(k)
+			if err != nil {
+				return err
+			}
+			keyStrs[i] = s
+		}
+		sort.Sort(byKeyString{keys, keyStrs})
+
+		for i, k := range keys {
+			childKey := fmt.Sprintf("%s[%s]", key, keyStrs[i])
+			if err := marshal(typ.Elem(), val.MapIndex(k), childKey, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		s, err := formatScalar(val)
+		if err != nil {
+			return err
+		}
+		values.Add(key, s)
+		return nil
+	}
+}
+
+// byKeyString sorts a slice of map keys together with their formatted string
+// representation, so the output of Marshal is deterministic regardless of
+// the map's key type.
+type byKeyString struct {
+	keys []reflect.Value
+	strs []string
+}
+
+func (b byKeyString) Len() int { return len(b.keys) }
+func (b byKeyString) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.strs[i], b.strs[j] = b.strs[j], b.strs[i]
+}
+func (b byKeyString) Less(i, j int) bool {
+	return b.strs[i] < b.strs[j]
+}
+
+// fieldTag returns the query key name and whether empty values should be
+// omitted, based on the StructTag tag of styp.
+func fieldTag(styp reflect.StructField) (name string, omitempty bool) {
+	tag := styp.Tag.Get(StructTag)
+	if tag == "" {
+		return styp.Name, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = styp.Name
+	}
+
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+
+	return name, omitempty
+}
+
+// marshalText encodes val using QueryMarshaler or, failing that,
+// encoding.TextMarshaler. It reports ok == false if val implements neither,
+// in which case the caller should fall back to the generic encoding below
+// (this is how e.g. time.Time, which implements encoding.TextMarshaler,
+// gets a sensible RFC3339 representation for free).
+func marshalText(val reflect.Value) (s string, ok bool, err error) {
+	if !val.CanInterface() {
+		return "", false, nil
+	}
+
+	if m, ok := val.Interface().(QueryMarshaler); ok {
+		s, err := m.MarshalQuery()
+		return s, true, err
+	}
+	if val.CanAddr() {
+		if m, ok := val.Addr().Interface().(QueryMarshaler); ok {
+			s, err := m.MarshalQuery()
+			return s, true, err
+		}
+	}
+
+	if m, ok := val.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), true, err
+	}
+	if val.CanAddr() {
+		if m, ok := val.Addr().Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			return string(b), true, err
+		}
+	}
+
+	return "", false, nil
+}
+
+// formatScalar formats val, which must be a string, integer, float or bool,
+// as a string. It is the inverse of getValue for these kinds.
+func formatScalar(val reflect.Value) (string, error) {
+	switch val.Kind() {
+	case reflect.String:
+		return val.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(val.Bool()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %v", val.Type())
+	}
+}
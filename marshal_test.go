@@ -0,0 +1,180 @@
+package qtos
+
+import (
+	"reflect"
+	"testing"
+)
+
+// roundTrip marshals v and unmarshals the result back into a new zero value
+// of the same type, then asserts it is equal to v.
+func roundTrip(t *testing.T, v interface{}) {
+	t.Helper()
+
+	values, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := reflect.New(reflect.TypeOf(v))
+	if err := Unmarshal(values, x.Interface()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := reflect.Indirect(x).Interface(); !reflect.DeepEqual(v, got) {
+		t.Fatalf("expected\n%#v\ngot\n%#v\nvalues: %v", v, got, values)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	type subsub struct {
+		Int int `query:"int"`
+	}
+
+	type sub struct {
+		Int         int         `query:"int"`
+		Interface   interface{} `query:"interface"`
+		SubSub      subsub      `query:"subsub"`
+		SliceString []string    `query:"slicestring"`
+	}
+
+	type base struct {
+		String               string              `query:"string"`
+		Int                  int                 `query:"int"`
+		Float                float64             `query:"float"`
+		Bool                 bool                `query:"bool"`
+		Interface            interface{}         `query:"interface"`
+		MapStringInt         map[string]int      `query:"mapstringint"`
+		MapIntString         map[int]string      `query:"mapintstring"`
+		SliceString          []string            `query:"slicestring"`
+		SliceInt             []int               `query:"sliceint"`
+		MapStringSliceString map[string][]string `query:"mapstringslicestring"`
+		Sub                  sub                 `query:"sub"`
+		MapStringSub         map[string]sub      `query:"mapstringsub"`
+		SliceMapIntInt       []map[int]int       `query:"slicemapintint"`
+	}
+
+	values := []interface{}{
+		base{String: "test"},
+		base{Int: 2},
+		base{Float: 2.3},
+		base{Bool: true},
+		base{Interface: "test"},
+		base{MapStringInt: map[string]int{"test": 2, "other": 3}},
+		base{MapIntString: map[int]string{2: "test", 10: "other"}},
+		base{SliceString: []string{"foo", "bar"}},
+		base{SliceInt: []int{2, 3}},
+		base{MapStringSliceString: map[string][]string{
+			"test": {"foo", "bar"},
+			"foo":  {"bar"},
+		}},
+		base{Sub: sub{Int: 2, Interface: "2", SubSub: subsub{Int: 3}}},
+		base{SliceMapIntInt: []map[int]int{{1: 2, 3: 4}}},
+		[]string{"foo", "bar"},
+		[]int{2, 3},
+		map[string]string{"foo": "bar"},
+		map[int]int{2: 3},
+	}
+
+	for _, v := range values {
+		t.Run(reflect.TypeOf(v).String(), func(t *testing.T) {
+			roundTrip(t, v)
+		})
+	}
+}
+
+func TestMarshalOmitempty(t *testing.T) {
+	type base struct {
+		String string `query:"string,omitempty"`
+		Int    int    `query:"int,omitempty"`
+	}
+
+	values, err := Marshal(base{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 0 {
+		t.Fatalf("expected no values, got %v", values)
+	}
+
+	roundTrip(t, base{String: "test", Int: 2})
+}
+
+func TestMarshalSkipField(t *testing.T) {
+	type base struct {
+		String string `query:"-"`
+		Int    int    `query:"int"`
+	}
+
+	values, err := Marshal(base{String: "test", Int: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := values["String"]; ok {
+		t.Fatal("expected String to be skipped")
+	}
+	if values.Get("int") != "2" {
+		t.Fatalf("expected int=2, got %v", values)
+	}
+}
+
+type queryPair struct {
+	value int
+}
+
+func (p queryPair) MarshalQuery() (string, error) {
+	return "pair-" + string(rune('0'+p.value)), nil
+}
+
+func (p *queryPair) UnmarshalQuery(value string) error {
+	p.value = int(value[len(value)-1] - '0')
+	return nil
+}
+
+func TestMarshalQueryMarshaler(t *testing.T) {
+	type base struct {
+		Pair queryPair `query:"pair"`
+	}
+
+	roundTrip(t, base{Pair: queryPair{value: 5}})
+}
+
+func TestMarshalPointer(t *testing.T) {
+	type base struct {
+		String *string `query:"string"`
+	}
+
+	s := "test"
+
+	values, err := Marshal(&base{String: &s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values.Get("string") != "test" {
+		t.Fatalf("expected string=test, got %v", values)
+	}
+
+	values, err = Marshal(base{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := values["string"]; ok {
+		t.Fatalf("expected a nil pointer to be skipped, got %v", values)
+	}
+}
+
+func TestMarshalUnsupportedType(t *testing.T) {
+	if _, err := Marshal(func() {}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMarshalNil(t *testing.T) {
+	var v interface{}
+	if _, err := Marshal(v); err == nil {
+		t.Fatal("expected an error")
+	}
+}
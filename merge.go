@@ -0,0 +1,225 @@
+package qtos
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// MergeConflictError is returned by Merge when dst and src both have a
+// non-zero, differing scalar value at the same path.
+type MergeConflictError struct {
+	Path string
+	Dst  interface{}
+	Src  interface{}
+}
+
+func (e *MergeConflictError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "<root>"
+	}
+	return fmt.Sprintf("qtos: conflicting values for %s: %v != %v", path, e.Dst, e.Src)
+}
+
+// MergeOption configures the behavior of Merge.
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	strict bool
+}
+
+// WithMergeStrict makes Merge return a *MergeConflictError identifying the
+// offending path when two non-zero scalar values conflict, instead of the
+// default of letting src overwrite dst.
+func WithMergeStrict(strict bool) MergeOption {
+	return func(o *mergeOptions) {
+		o.strict = strict
+	}
+}
+
+// Merge recursively merges src into the value pointed to by dst. dst and src
+// must be the same type (src may additionally be a pointer to that type).
+// Slices are merged by index, growing dst to fit src. Maps are unioned,
+// merging values that exist in both. Struct fields, pointers and
+// interface-typed fields are merged recursively. For any other value, a
+// zero src is ignored and a zero dst is overwritten by src. Two differing
+// non-zero values are resolved by letting src win, unless WithMergeStrict
+// is passed, in which case they produce a *MergeConflictError identifying
+// the offending path.
+//
+// This lets callers layer values decoded from multiple sources, such as
+// defaults, a query string and request-specific overrides.
+func Merge(dst, src interface{}, opts ...MergeOption) error {
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr || dstVal.IsNil() {
+		return fmt.Errorf("dst must be a non-nil pointer")
+	}
+	dstVal = dstVal.Elem()
+
+	srcVal := reflect.ValueOf(src)
+	if srcVal.Kind() == reflect.Ptr {
+		if srcVal.IsNil() {
+			return nil
+		}
+		srcVal = srcVal.Elem()
+	}
+
+	if dstVal.Type() != srcVal.Type() {
+		return fmt.Errorf("dst and src must be the same type, got %v and %v", dstVal.Type(), srcVal.Type())
+	}
+
+	o := &mergeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return mergeInto(dstVal, srcVal, "", o)
+}
+
+// mergeInto merges src into dst, which must be addressable. path is the
+// dotted/bracketed location of dst, used in MergeConflictError.
+func mergeInto(dst, src reflect.Value, path string, o *mergeOptions) error {
+	typ := dst.Type()
+
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		if src.IsNil() {
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(typ.Elem()))
+		}
+		return mergeInto(dst.Elem(), src.Elem(), path, o)
+
+	case typ.Kind() == reflect.Interface:
+		if src.IsNil() {
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(src)
+			return nil
+		}
+		if dst.Elem().Type() != src.Elem().Type() {
+			if o.strict {
+				return &MergeConflictError{Path: path, Dst: dst.Interface(), Src: src.Interface()}
+			}
+			dst.Set(src)
+			return nil
+		}
+
+		// dst.Elem() isn't addressable, so merge into a settable copy.
+		v := reflect.New(dst.Elem().Type()).Elem()
+		v.Set(dst.Elem())
+		if err := mergeInto(v, src.Elem(), path, o); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+
+	case typ == timeType:
+		st := src.Interface().(time.Time)
+		dt := dst.Interface().(time.Time)
+		if st.IsZero() {
+			return nil
+		}
+		if dt.IsZero() {
+			dst.Set(src)
+			return nil
+		}
+		if dt.Equal(st) {
+			return nil
+		}
+		if o.strict {
+			return &MergeConflictError{Path: path, Dst: dst.Interface(), Src: src.Interface()}
+		}
+		dst.Set(src)
+		return nil
+
+	case typ.Kind() == reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			styp := typ.Field(i)
+
+			// Only exported fields can be merged.
+			if styp.PkgPath != "" {
+				continue
+			}
+
+			fieldPath := styp.Name
+			if path != "" {
+				fieldPath = path + "." + styp.Name
+			}
+
+			if err := mergeInto(dst.Field(i), src.Field(i), fieldPath, o); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case typ.Kind() == reflect.Slice:
+		if src.IsNil() {
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeSlice(typ, src.Len(), src.Len()))
+		} else if dst.Len() < src.Len() {
+			n := reflect.MakeSlice(typ, src.Len(), src.Len())
+			reflect.Copy(n, dst)
+			dst.Set(n)
+		}
+		fallthrough
+
+	case typ.Kind() == reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			if err := mergeInto(dst.Index(i), src.Index(i), fmt.Sprintf("%s[%d]", path, i), o); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case typ.Kind() == reflect.Map:
+		if src.IsNil() {
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(typ))
+		}
+
+		for _, k := range src.MapKeys() {
+			sv := src.MapIndex(k)
+			keyPath := fmt.Sprintf("%s[%v]", path, k.Interface())
+
+			dv := dst.MapIndex(k)
+			if !dv.IsValid() {
+				dst.SetMapIndex(k, sv)
+				continue
+			}
+
+			// dst.MapIndex isn't addressable, so merge into a settable copy.
+			v := reflect.New(typ.Elem()).Elem()
+			v.Set(dv)
+			if err := mergeInto(v, sv, keyPath, o); err != nil {
+				return err
+			}
+			dst.SetMapIndex(k, v)
+		}
+		return nil
+
+	default:
+		if src.IsZero() {
+			return nil
+		}
+		if dst.IsZero() {
+			dst.Set(src)
+			return nil
+		}
+		if reflect.DeepEqual(dst.Interface(), src.Interface()) {
+			return nil
+		}
+		if o.strict {
+			return &MergeConflictError{Path: path, Dst: dst.Interface(), Src: src.Interface()}
+		}
+		dst.Set(src)
+		return nil
+	}
+}
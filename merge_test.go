@@ -0,0 +1,165 @@
+package qtos
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	type sub struct {
+		Int int `query:"int"`
+	}
+
+	type base struct {
+		String    string            `query:"string"`
+		Int       int               `query:"int"`
+		Sub       sub               `query:"sub"`
+		PtrSub    *sub              `query:"ptrsub"`
+		Interface interface{}       `query:"interface"`
+		Slice     []string          `query:"slice"`
+		Map       map[string]string `query:"map"`
+		Time      time.Time         `query:"time"`
+	}
+
+	t1 := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		dst, src, want base
+	}{
+		"scalar src overwrites zero dst": {
+			dst:  base{},
+			src:  base{String: "a"},
+			want: base{String: "a"},
+		},
+		"zero src leaves dst untouched": {
+			dst:  base{String: "a"},
+			src:  base{},
+			want: base{String: "a"},
+		},
+		"equal scalars are a no-op": {
+			dst:  base{Int: 2},
+			src:  base{Int: 2},
+			want: base{Int: 2},
+		},
+		"nested struct fields merge independently": {
+			dst:  base{Sub: sub{Int: 1}},
+			src:  base{String: "a"},
+			want: base{String: "a", Sub: sub{Int: 1}},
+		},
+		"nil ptr dst is allocated from src": {
+			dst:  base{},
+			src:  base{PtrSub: &sub{Int: 5}},
+			want: base{PtrSub: &sub{Int: 5}},
+		},
+		"nil interface dst takes src": {
+			dst:  base{},
+			src:  base{Interface: "x"},
+			want: base{Interface: "x"},
+		},
+		"slice grows and merges by index": {
+			dst:  base{Slice: []string{"a"}},
+			src:  base{Slice: []string{"", "b"}},
+			want: base{Slice: []string{"a", "b"}},
+		},
+		"maps are unioned": {
+			dst:  base{Map: map[string]string{"a": "1"}},
+			src:  base{Map: map[string]string{"b": "2"}},
+			want: base{Map: map[string]string{"a": "1", "b": "2"}},
+		},
+		"zero time src leaves dst untouched": {
+			dst:  base{Time: t1},
+			src:  base{},
+			want: base{Time: t1},
+		},
+		"non-zero time src overwrites zero dst": {
+			dst:  base{},
+			src:  base{Time: t1},
+			want: base{Time: t1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dst := tc.dst
+			if err := Merge(&dst, tc.src); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dst, tc.want) {
+				t.Fatalf("expected\n%#v\ngot\n%#v", tc.want, dst)
+			}
+		})
+	}
+}
+
+func TestMergeOverwrite(t *testing.T) {
+	// By default, conflicting non-zero scalars are resolved by letting src
+	// win, so defaults can be layered with request-specific overrides.
+	type base struct {
+		Timeout int `query:"timeout"`
+	}
+
+	dst := base{Timeout: 30}
+	if err := Merge(&dst, base{Timeout: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Timeout != 60 {
+		t.Fatalf("expected src to win, got %#v", dst)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	type base struct {
+		String string `query:"string"`
+	}
+
+	dst := base{String: "a"}
+	err := Merge(&dst, base{String: "b"}, WithMergeStrict(true))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	conflict, ok := err.(*MergeConflictError)
+	if !ok {
+		t.Fatalf("expected a *MergeConflictError, got %T: %v", err, err)
+	}
+	if conflict.Path != "String" {
+		t.Fatalf("expected path %q, got %q", "String", conflict.Path)
+	}
+}
+
+func TestMergeIndexedSliceMatchesRepeatedKeyDecode(t *testing.T) {
+	// Mirrors the repeated indexed key behavior covered by TestUnmarshal:
+	// later sources win per-index, earlier indices are preserved when the
+	// later source doesn't set them.
+	type base struct {
+		SliceString []string `query:"slicestring"`
+	}
+
+	defaults := base{SliceString: []string{"a", "b"}}
+	override := base{SliceString: []string{"", "", "c"}}
+
+	if err := Merge(&defaults, override); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(defaults.SliceString, want) {
+		t.Fatalf("expected %#v, got %#v", want, defaults.SliceString)
+	}
+}
+
+func TestMergeNotAPointer(t *testing.T) {
+	type base struct {
+		String string `query:"string"`
+	}
+
+	if err := Merge(base{}, base{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMergeTypeMismatch(t *testing.T) {
+	var dst string
+	if err := Merge(&dst, 2); err == nil {
+		t.Fatal("expected an error")
+	}
+}
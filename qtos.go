@@ -1,12 +1,16 @@
 package qtos
 
 import (
+	"database/sql"
+	"encoding"
 	"fmt"
 	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -14,36 +18,45 @@ var (
 	// Assigning to this variable is not thread safe.
 	StructTag = "query"
 
-	fieldRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
-	indexRegexp  = regexp.MustCompile(`^\[[0-9]+\]`)
-	mapKeyRegexp = regexp.MustCompile(`^\[[^\]]+\]`)
+	// TimeLayouts is the list of layouts tried, in order, when parsing a
+	// time.Time value. The first layout that parses successfully is used.
+	// Assigning to this variable is not thread safe.
+	TimeLayouts = []string{time.RFC3339}
+
+	fieldRegexp        = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+	indexRegexp        = regexp.MustCompile(`^\[[0-9]+\]`)
+	mapKeyRegexp       = regexp.MustCompile(`^\[[^\]]+\]`)
+	fieldBracketRegexp = regexp.MustCompile(`^\[[a-zA-Z_][a-zA-Z0-9_]*\]`)
+
+	timeType              = reflect.TypeOf(time.Time{})
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	scannerType           = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
 )
 
 // Unmarshal parses the url values and stores the result in the value pointed to by v
 // For examples and supported formats see the tests.
 func Unmarshal(values url.Values, v interface{}) error {
-	typ := reflect.TypeOf(v)
-	val := reflect.ValueOf(v)
+	return NewDecoder().Decode(values, v)
+}
 
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return fmt.Errorf("v must be a pointer")
-	} else {
-		typ = typ.Elem()
-		val = val.Elem()
+// bind is called recursively while parsing the value name.
+// base contains the parsed part so far and is used to produce nice error messages
+// and the key passed to the unknown key function.
+// depth counts the number of struct fields, slice indices and map keys consumed so far.
+func (d *Decoder) bind(typ reflect.Type, val reflect.Value, base, name string, value []string, depth int) error {
+	if d.maxDepth > 0 && depth > d.maxDepth {
+		return fmt.Errorf("max depth of %d exceeded for %s", d.maxDepth, base+name)
 	}
 
-	for name, value := range values {
-		if err := bind(typ, val, "", name, value); err != nil {
-			return err
+	// Auto-allocate and descend into pointer field types.
+	if typ.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val.Set(reflect.New(typ.Elem()))
 		}
+		return d.bind(typ.Elem(), val.Elem(), base, name, value, depth)
 	}
 
-	return nil
-}
-
-// bind is called recursively while parsing the value name.
-// base contains the parsed part so far and is only used to product nice error messages.
-func bind(typ reflect.Type, val reflect.Value, base, name string, value []string) error {
 	// If the name is empty it means we should assign value to val.
 	if name == "" {
 		if len(value) > 1 {
@@ -58,24 +71,37 @@ func bind(typ reflect.Type, val reflect.Value, base, name string, value []string
 	}
 
 	// We can ignore leading dots.
+	hadDot := false
 	if name[0] == '.' {
 		base = base + "."
 		name = name[1:]
+		hadDot = true
 	}
 
-	// Is it a struct field?
+	// Is it a struct field, or - for a map with string keys, when the key
+	// style allows it - the dot equivalent of "map[key]"?
 	if field := fieldRegexp.FindString(name); field != "" {
-		if typ.Kind() != reflect.Struct {
+		switch {
+		case typ.Kind() == reflect.Struct:
+			if hadDot && d.keyStyle == BracketStyle {
+				return fmt.Errorf("expected %s to use bracket notation instead of dot notation", base+field)
+			}
+			return d.bindStructField(typ, val, base, field, name[len(field):], value, depth)
+		case hadDot && typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String &&
+			(d.keyStyle == BracketStyle || d.keyStyle == BothStyles):
+			return d.bindMapKey(typ, val, base+field, field, name[len(field):], value, depth)
+		default:
 			return fmt.Errorf("expected a struct for %s got %v", base, typ)
 		}
+	}
 
-		i, ok := getStructField(typ, field)
-		if !ok {
-			// The struct doesn't have any field with this name.
-			return nil
+	// Is it a struct field using bracket notation, the alternative some
+	// front-ends emit for nested structs instead of "sub.field"?
+	if typ.Kind() == reflect.Struct && (d.keyStyle == BracketStyle || d.keyStyle == BothStyles) {
+		if key := fieldBracketRegexp.FindString(name); key != "" {
+			field := key[1 : len(key)-1]
+			return d.bindStructField(typ, val, base, field, name[len(key):], value, depth)
 		}
-
-		return bind(typ.Field(i).Type, val.Field(i), base+name[:len(field)], name[len(field):], value)
 	}
 
 	// Is it a slice?
@@ -121,7 +147,7 @@ func bind(typ reflect.Type, val reflect.Value, base, name string, value []string
 			t := typ.Elem()
 			v := reflect.Indirect(reflect.New(t))
 
-			if err := bind(t, v, base+name[:len(indexStr)], name[len(indexStr):], value); err != nil {
+			if err := d.bind(t, v, base+name[:len(indexStr)], name[len(indexStr):], value, depth+1); err != nil {
 				return err
 			} else if mv, err := mergeValues(val.Index(index), v); err != nil {
 				return err
@@ -138,29 +164,59 @@ func bind(typ reflect.Type, val reflect.Value, base, name string, value []string
 			return fmt.Errorf("expected a map for %s got %v", base, typ)
 		}
 
-		if val.IsNil() {
-			// Create the map if it doesn't exist yet.
-			val.Set(reflect.MakeMap(typ))
+		// BracketStyle reserves "map[key]" for maps that can't be accessed
+		// with "map.key" instead, i.e. those with a non-string key type.
+		if d.keyStyle == BracketStyle && typ.Key().Kind() == reflect.String {
+			return fmt.Errorf("expected %s to use dot notation instead of bracket notation", base+key)
 		}
 
-		t := typ.Elem()
-		v := reflect.Indirect(reflect.New(t))
+		return d.bindMapKey(typ, val, base+name[:len(key)], key[1:len(key)-1], name[len(key):], value, depth)
+	}
 
-		if err := bind(t, v, base+name[:len(key)], name[len(key):], value); err != nil {
-			return err
-		} else {
-			if k, err := getValue(typ.Key(), key[1:len(key)-1]); err != nil {
-				return err
-			} else if mv, err := mergeValues(val.MapIndex(k), v); err != nil {
-				return err
-			} else {
-				val.SetMapIndex(k, mv)
-				return nil
-			}
+	return fmt.Errorf("unknown format %s in %s", name, base+name)
+}
+
+// bindStructField looks up field on typ and, if found, binds the remainder
+// of the key against it.
+func (d *Decoder) bindStructField(typ reflect.Type, val reflect.Value, base, field, rest string, value []string, depth int) error {
+	i, ok := getStructField(typ, d.tag, field)
+	if !ok {
+		if d.unknownKeyFunc != nil {
+			return d.unknownKeyFunc(base+field+rest, value)
+		}
+		if d.strict {
+			return fmt.Errorf("unknown field %s in %s", field, base+field+rest)
 		}
+		// The struct doesn't have any field with this name.
+		return nil
 	}
 
-	return fmt.Errorf("unknown format %s in %s", name, base+name)
+	return d.bind(typ.Field(i).Type, val.Field(i), base+field, rest, value, depth+1)
+}
+
+// bindMapKey binds the remainder of the key against typ's map value type at
+// the given string key, creating the map if necessary.
+func (d *Decoder) bindMapKey(typ reflect.Type, val reflect.Value, elemBase, key, rest string, value []string, depth int) error {
+	if val.IsNil() {
+		// Create the map if it doesn't exist yet.
+		val.Set(reflect.MakeMap(typ))
+	}
+
+	t := typ.Elem()
+	v := reflect.Indirect(reflect.New(t))
+
+	if err := d.bind(t, v, elemBase, rest, value, depth+1); err != nil {
+		return err
+	}
+
+	if k, err := getValue(typ.Key(), key); err != nil {
+		return err
+	} else if mv, err := mergeValues(val.MapIndex(k), v); err != nil {
+		return err
+	} else {
+		val.SetMapIndex(k, mv)
+		return nil
+	}
 }
 
 func mergeValues(a, b reflect.Value) (reflect.Value, error) {
@@ -197,6 +253,20 @@ func mergeValues(a, b reflect.Value) (reflect.Value, error) {
 			a.SetMapIndex(k, b.MapIndex(k))
 		}
 
+		return a, nil
+	case reflect.Ptr:
+		if b.IsNil() {
+			return a, nil
+		}
+		if a.IsNil() {
+			return b, nil
+		}
+
+		mv, err := mergeValues(a.Elem(), b.Elem())
+		if err != nil {
+			return a, err
+		}
+		a.Elem().Set(mv)
 		return a, nil
 	default:
 		// For most values like string we just return the second value
@@ -207,10 +277,55 @@ func mergeValues(a, b reflect.Value) (reflect.Value, error) {
 
 // getValue returns value as a reflect.Value of type typ.
 func getValue(typ reflect.Type, value string) (reflect.Value, error) {
+	if reflect.PtrTo(typ).Implements(queryUnmarshalerType) {
+		v := reflect.New(typ)
+		if err := v.Interface().(QueryUnmarshaler).UnmarshalQuery(value); err != nil {
+			return reflect.Zero(typ), err
+		}
+		return v.Elem(), nil
+	}
+
+	if typ == timeType {
+		for i, layout := range TimeLayouts {
+			t, err := time.Parse(layout, value)
+			if err == nil {
+				return reflect.ValueOf(t), nil
+			} else if i == len(TimeLayouts)-1 {
+				return reflect.Zero(typ), err
+			}
+		}
+	}
+
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		v := reflect.New(typ)
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return reflect.Zero(typ), err
+		}
+		return v.Elem(), nil
+	}
+
+	if reflect.PtrTo(typ).Implements(binaryUnmarshalerType) {
+		v := reflect.New(typ)
+		if err := v.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(value)); err != nil {
+			return reflect.Zero(typ), err
+		}
+		return v.Elem(), nil
+	}
+
+	// Types like sql.NullString that implement sql.Scanner but none of the
+	// above interfaces, e.g. database/sql's Null* wrapper types.
+	if reflect.PtrTo(typ).Implements(scannerType) {
+		v := reflect.New(typ)
+		if err := v.Interface().(sql.Scanner).Scan(value); err != nil {
+			return reflect.Zero(typ), err
+		}
+		return v.Elem(), nil
+	}
+
 	switch typ.Kind() {
 	case reflect.String:
 		return reflect.ValueOf(value), nil
-	case reflect.Int, reflect.Int32, reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if i, err := strconv.ParseInt(value, 10, 64); err != nil {
 			return reflect.Zero(typ), err
 		} else {
@@ -218,6 +333,14 @@ func getValue(typ reflect.Type, value string) (reflect.Value, error) {
 			v.SetInt(i)
 			return v, nil
 		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return reflect.Zero(typ), err
+		} else {
+			v := reflect.Indirect(reflect.New(typ))
+			v.SetUint(i)
+			return v, nil
+		}
 	case reflect.Float32, reflect.Float64:
 		if f, err := strconv.ParseFloat(value, 64); err != nil {
 			return reflect.Zero(typ), err
@@ -241,26 +364,43 @@ func getValue(typ reflect.Type, value string) (reflect.Value, error) {
 	}
 }
 
-// getStructField returns the field index in typ of the field with struct
-// tag name.
-func getStructField(typ reflect.Type, name string) (int, bool) {
-	// TODO: In theory we could add a global case that caches this mapping
-	// based on typ.Name(). It would require a mutex so some benchmarking
-	// is required to see if this actually improves the speed.
-	mapping := make(map[string]int)
+// structFieldCacheKey identifies a cached tag-name-to-field-index mapping.
+// The tag is part of the key because different Decoders may use different
+// struct tags to look up fields on the same type.
+type structFieldCacheKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// structFieldCache caches the tag-name-to-field-index mapping built by
+// getStructField, keyed by structFieldCacheKey. Entries are only ever added,
+// never mutated or removed, so reads and writes are safe to do concurrently.
+var structFieldCache sync.Map // map[structFieldCacheKey]map[string]int
 
-	for i := 0; i < typ.NumField(); i++ {
-		styp := typ.Field(i)
+// getStructField returns the field index in typ of the field with the given
+// tag name under the given struct tag key.
+func getStructField(typ reflect.Type, tag, name string) (int, bool) {
+	key := structFieldCacheKey{typ, tag}
 
-		valueName := styp.Tag.Get(StructTag)
+	mapping, ok := structFieldCache.Load(key)
+	if !ok {
+		m := make(map[string]int, typ.NumField())
+
+		for i := 0; i < typ.NumField(); i++ {
+			styp := typ.Field(i)
+
+			valueName := strings.Split(styp.Tag.Get(tag), ",")[0]
+
+			if valueName == "" {
+				valueName = styp.Name
+			}
 
-		if valueName == "" {
-			valueName = styp.Name
+			m[valueName] = i
 		}
 
-		mapping[valueName] = i
+		mapping, _ = structFieldCache.LoadOrStore(key, m)
 	}
 
-	i, ok := mapping[name]
+	i, ok := mapping.(map[string]int)[name]
 	return i, ok
 }
@@ -0,0 +1,99 @@
+package qtos
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// benchStruct has 20 fields, each a map decoded from two keys below, for a
+// total of 40 query keys, to approximate a realistic request struct.
+type benchStruct struct {
+	Field0  map[string]string `query:"field0"`
+	Field1  map[string]string `query:"field1"`
+	Field2  map[string]string `query:"field2"`
+	Field3  map[string]string `query:"field3"`
+	Field4  map[string]string `query:"field4"`
+	Field5  map[string]string `query:"field5"`
+	Field6  map[string]string `query:"field6"`
+	Field7  map[string]string `query:"field7"`
+	Field8  map[string]string `query:"field8"`
+	Field9  map[string]string `query:"field9"`
+	Field10 map[string]string `query:"field10"`
+	Field11 map[string]string `query:"field11"`
+	Field12 map[string]string `query:"field12"`
+	Field13 map[string]string `query:"field13"`
+	Field14 map[string]string `query:"field14"`
+	Field15 map[string]string `query:"field15"`
+	Field16 map[string]string `query:"field16"`
+	Field17 map[string]string `query:"field17"`
+	Field18 map[string]string `query:"field18"`
+	Field19 map[string]string `query:"field19"`
+}
+
+func benchValues() url.Values {
+	values := make(url.Values, 40)
+	for i := 0; i < 20; i++ {
+		values.Add(fmt.Sprintf("field%d[a]", i), fmt.Sprintf("value%da", i))
+		values.Add(fmt.Sprintf("field%d[b]", i), fmt.Sprintf("value%db", i))
+	}
+	return values
+}
+
+// BenchmarkGetStructField exercises the sync.Map cache directly: the first
+// lookup for a given type builds the mapping, every subsequent lookup (here,
+// the other 19 names and all 20 in later iterations) is a plain map read.
+func BenchmarkGetStructField(b *testing.B) {
+	typ := reflect.TypeOf(benchStruct{})
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = fmt.Sprintf("field%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			getStructField(typ, StructTag, name)
+		}
+	}
+}
+
+// BenchmarkUnmarshal decodes a 20 field struct from 40 keys, which is the
+// O(N·K) hot path the cache in getStructField is meant to fix.
+func BenchmarkUnmarshal(b *testing.B) {
+	values := benchValues()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var v benchStruct
+		if err := Unmarshal(values, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestUnmarshalConcurrent exercises getStructField's cache from many
+// goroutines at once; run with -race to verify it is safe.
+func TestUnmarshalConcurrent(t *testing.T) {
+	values := benchValues()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var v benchStruct
+			if err := Unmarshal(values, &v); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
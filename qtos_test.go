@@ -1,11 +1,30 @@
 package qtos
 
 import (
+	"database/sql"
 	"net/url"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
+// hexInt implements encoding.TextUnmarshaler to decode a hexadecimal string.
+type hexInt int
+
+func (h *hexInt) UnmarshalText(text []byte) error {
+	i, err := strconv.ParseInt(string(text), 16, 64)
+	if err != nil {
+		return err
+	}
+	*h = hexInt(i)
+	return nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
 func TestUnmarshal(t *testing.T) {
 	type subsub struct {
 		Int int `query:"int"`
@@ -36,6 +55,12 @@ func TestUnmarshal(t *testing.T) {
 		Sub                  sub                   `query:"sub"`
 		MapStringSub         map[string]sub        `query:"mapstringsub"`
 		SliceMapIntInt       []map[int]int         `query:"slicemapintint"`
+		Uint                 uint                  `query:"uint"`
+		PtrInt               *int                  `query:"ptrint"`
+		PtrSub               *sub                  `query:"ptrsub"`
+		Time                 time.Time             `query:"time"`
+		Hex                  hexInt                `query:"hex"`
+		Null                 sql.NullString        `query:"null"`
 	}
 
 	inputs := map[string]interface{}{
@@ -143,6 +168,26 @@ func TestUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		"uint=2": base{
+			Uint: 2,
+		},
+		"ptrint=2": base{
+			PtrInt: intPtr(2),
+		},
+		"ptrsub.int=2": base{
+			PtrSub: &sub{
+				Int: 2,
+			},
+		},
+		"time=2021-02-03T04:05:06Z": base{
+			Time: time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC),
+		},
+		"hex=ff": base{
+			Hex: 255,
+		},
+		"null=hello": base{
+			Null: sql.NullString{String: "hello", Valid: true},
+		},
 
 		// Test some none-struct values.
 		"[]=foo&[]=bar": []string{"foo", "bar"},